@@ -0,0 +1,255 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pion/ion/pkg/log"
+	"github.com/pion/ion/pkg/rtc/plugins/rtppb"
+	"github.com/pion/rtp"
+	"google.golang.org/grpc"
+)
+
+// ExternalPluginConfig describes an out-of-process plugin binary that
+// speaks the RTPPlugin gRPC service over a unix-domain socket.
+type ExternalPluginConfig struct {
+	ID   string
+	On   bool
+	Path string
+	Args []string
+	Env  []string
+}
+
+// ExternalPlugin spawns Path as a child process, dials it over a
+// unix-domain socket and wraps the resulting gRPC stub so it satisfies
+// the normal Plugin interface. From the rest of PluginChain's point of
+// view an ExternalPlugin is indistinguishable from an in-process one.
+type ExternalPlugin struct {
+	config ExternalPluginConfig
+
+	cmd    *exec.Cmd
+	conn   *grpc.ClientConn
+	client rtppb.RTPPluginClient
+
+	sockPath string
+
+	writeStream rtppb.RTPPlugin_WriteRTPClient
+	readStream  rtppb.RTPPlugin_ReadRTPClient
+
+	outRTPChan chan *rtp.Packet
+
+	ackErrLock sync.Mutex
+	ackErr     error
+
+	stop     bool
+	stopLock sync.Mutex
+}
+
+// NewExternalPlugin spawns the plugin binary described by config,
+// negotiates the socket transport and opens the WriteRTP/ReadRTP
+// streams. The child is expected to create its gRPC server on the
+// socket path passed to it via the ION_PLUGIN_SOCK environment
+// variable before this call returns.
+func NewExternalPlugin(config ExternalPluginConfig) (*ExternalPlugin, error) {
+	sockDir, err := os.MkdirTemp("", "ion-plugin-"+config.ID)
+	if err != nil {
+		return nil, err
+	}
+	sockPath := filepath.Join(sockDir, "plugin.sock")
+
+	cmd := exec.Command(config.Path, config.Args...)
+	cmd.Env = append(os.Environ(), config.Env...)
+	cmd.Env = append(cmd.Env, "ION_PLUGIN_SOCK="+sockPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(sockDir)
+		return nil, err
+	}
+
+	conn, err := dialPluginSocket(sockPath, 5*time.Second)
+	if err != nil {
+		cmd.Process.Kill()
+		os.RemoveAll(sockDir)
+		return nil, err
+	}
+
+	client := rtppb.NewRTPPluginClient(conn)
+
+	writeStream, err := client.WriteRTP(context.Background())
+	if err != nil {
+		conn.Close()
+		cmd.Process.Kill()
+		os.RemoveAll(sockDir)
+		return nil, err
+	}
+
+	readStream, err := client.ReadRTP(context.Background(), &rtppb.Empty{})
+	if err != nil {
+		conn.Close()
+		cmd.Process.Kill()
+		os.RemoveAll(sockDir)
+		return nil, err
+	}
+
+	e := &ExternalPlugin{
+		config:      config,
+		cmd:         cmd,
+		conn:        conn,
+		client:      client,
+		sockPath:    sockPath,
+		writeStream: writeStream,
+		readStream:  readStream,
+		outRTPChan:  make(chan *rtp.Packet, maxSize),
+	}
+
+	go e.readLoop()
+	go e.ackLoop()
+
+	return e, nil
+}
+
+// dialPluginSocket waits for the child to create the unix socket and
+// dials it, retrying until timeout since process startup and socket
+// creation race.
+func dialPluginSocket(sockPath string, timeout time.Duration) (*grpc.ClientConn, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := grpc.Dial(
+			"unix://"+sockPath,
+			grpc.WithInsecure(),
+			grpc.WithBlock(),
+			grpc.WithTimeout(200*time.Millisecond),
+		)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("external plugin: timed out dialing %s: %w", sockPath, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (e *ExternalPlugin) readLoop() {
+	defer close(e.outRTPChan)
+	for {
+		pkt, err := e.readStream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Errorf("ExternalPlugin(%s) ReadRTP error => %+v", e.config.ID, err)
+			return
+		}
+
+		p := &rtp.Packet{}
+		if err := p.Unmarshal(pkt.Data); err != nil {
+			log.Errorf("ExternalPlugin(%s) Unmarshal error => %+v", e.config.ID, err)
+			continue
+		}
+		e.outRTPChan <- p
+	}
+}
+
+// ackLoop drains WriteRTP acks off the stream as they arrive, rather
+// than WriteRTP blocking on a Recv per packet. That would serialize
+// every RTP packet behind a full round trip to the plugin; instead the
+// send side runs free and any error the plugin reports in an Ack
+// surfaces on the next WriteRTP call.
+func (e *ExternalPlugin) ackLoop() {
+	for {
+		ack, err := e.writeStream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			e.setAckErr(err)
+			return
+		}
+		if ack.Error != "" {
+			e.setAckErr(errors.New(ack.Error))
+		}
+	}
+}
+
+func (e *ExternalPlugin) setAckErr(err error) {
+	e.ackErrLock.Lock()
+	e.ackErr = err
+	e.ackErrLock.Unlock()
+}
+
+// takeAckErr returns and clears the most recently reported ack error,
+// if any.
+func (e *ExternalPlugin) takeAckErr() error {
+	e.ackErrLock.Lock()
+	defer e.ackErrLock.Unlock()
+	err := e.ackErr
+	e.ackErr = nil
+	return err
+}
+
+// ID implements Plugin.
+func (e *ExternalPlugin) ID() string {
+	return e.config.ID
+}
+
+// WriteRTP implements Plugin. The packet is marshaled to bytes with its
+// existing Marshal() so the gRPC boundary introduces no new wire format.
+// The send is non-blocking with respect to acks: it does not wait for
+// the plugin's Ack before returning, so a slow plugin can't serialize
+// the whole media path behind a round trip per packet. Any error the
+// plugin reported via a previous Ack is surfaced here instead of being
+// silently dropped.
+func (e *ExternalPlugin) WriteRTP(pkt *rtp.Packet) error {
+	if err := e.takeAckErr(); err != nil {
+		return err
+	}
+
+	data, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return e.writeStream.Send(&rtppb.Packet{Data: data})
+}
+
+// ReadRTP implements Plugin.
+func (e *ExternalPlugin) ReadRTP() <-chan *rtp.Packet {
+	return e.outRTPChan
+}
+
+// Stop implements Plugin. It asks the plugin to shut down cleanly before
+// killing the child process.
+func (e *ExternalPlugin) Stop() {
+	e.stopLock.Lock()
+	defer e.stopLock.Unlock()
+	if e.stop {
+		return
+	}
+	e.stop = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := e.client.Stop(ctx, &rtppb.Empty{}); err != nil {
+		log.Errorf("ExternalPlugin(%s) Stop error => %+v", e.config.ID, err)
+	}
+
+	e.writeStream.CloseSend()
+	e.conn.Close()
+
+	if e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	e.cmd.Wait()
+
+	os.RemoveAll(filepath.Dir(e.sockPath))
+}
@@ -2,7 +2,6 @@ package plugins
 
 import (
 	"errors"
-	"io"
 	"sync"
 
 	"github.com/pion/ion/pkg/log"
@@ -12,6 +11,22 @@ import (
 
 var (
 	errInvalidPlugins = errors.New("invalid plugins, make sure at least one plugin is on")
+
+	// errExternalNeedsHead guards against an ExternalPlugin ending up
+	// as the head of the chain: AttachPub only ever wires pub input
+	// into JitterBuffer or SampleBuilder, so a chain with neither on
+	// would never receive a single packet.
+	errExternalNeedsHead = errors.New("invalid plugins, External requires JitterBuffer or SampleBuilder to be on so it is never the head of the chain")
+
+	// errUpstreamFailed is forwardRTP's sentinel for "prevSv's plugin
+	// closed its ReadRTP channel and prevSv is permanently Failed". A
+	// closed channel from an upstream that is not Failed, or done
+	// firing, ends forwardRTP cleanly with nil instead. run() treats it
+	// specially: there's nothing left to retry against a dead upstream,
+	// so it fails this plugin too instead of burning through its own
+	// crash-restart budget against an input that will never produce
+	// another packet.
+	errUpstreamFailed = errors.New("plugins: upstream plugin permanently failed")
 )
 
 // Plugin some interfaces
@@ -27,6 +42,7 @@ const (
 	TypeRTPForwarder  = "RTPForwarder"
 	TypeSampleBuilder = "SampleBuilder"
 	TypeWebmSaver     = "WebmSaver"
+	TypeExternal      = "External"
 
 	maxSize = 100
 )
@@ -37,14 +53,23 @@ type Config struct {
 	RTPForwarder  RTPForwarderConfig
 	SampleBuilder SampleBuilderConfig
 	WebmSaver     WebmSaverConfig
+	External      ExternalPluginConfig
+
+	// Registry and PluginIDs let operators add plugins discovered from
+	// manifests on disk without a matching hard-coded branch below: set
+	// Registry to a *PluginRegistry and list the digest IDs to
+	// instantiate in PluginIDs.
+	Registry  *PluginRegistry
+	PluginIDs []string
 }
 
 type PluginChain struct {
-	mid        string
-	plugins    []Plugin
-	pluginLock sync.RWMutex
-	stop       bool
-	config     Config
+	mid         string
+	plugins     []Plugin
+	supervisors map[string]*Supervisor
+	pluginLock  sync.RWMutex
+	stop        bool
+	config      Config
 }
 
 func NewPluginChain(mid string) *PluginChain {
@@ -91,10 +116,18 @@ func CheckPlugins(config Config) error {
 		oneOn = true
 	}
 
+	if config.External.On {
+		oneOn = true
+	}
+
 	if !oneOn {
 		return errInvalidPlugins
 	}
 
+	if config.External.On && !config.JitterBuffer.On && !config.SampleBuilder.On {
+		return errExternalNeedsHead
+	}
+
 	return nil
 }
 
@@ -129,30 +162,105 @@ func (p *PluginChain) Init(config Config) error {
 		p.AddPlugin(TypeWebmSaver, NewWebmSaver(config.WebmSaver))
 	}
 
-	// forward packets along plugin chain
+	// then, add any out-of-process plugins
+	if config.External.On {
+		log.Infof("PluginChain.Init config.External.On=true config=%v", config.External)
+		config.External.ID = TypeExternal
+		extConfig := config.External
+		external, err := NewExternalPlugin(extConfig)
+		if err != nil {
+			log.Errorf("PluginChain.Init NewExternalPlugin error => %+v", err)
+			return err
+		}
+		p.AddPluginWithFactory(TypeExternal, external, func() (Plugin, error) {
+			return NewExternalPlugin(extConfig)
+		})
+	}
+
+	// last, add any registry-discovered plugins by ID
+	for _, id := range config.PluginIDs {
+		if config.Registry == nil {
+			log.Errorf("PluginChain.Init PluginIDs set but Registry is nil, skipping %s", id)
+			continue
+		}
+		id := id
+		registry := config.Registry
+		plugin, err := registry.NewPlugin(id)
+		if err != nil {
+			log.Errorf("PluginChain.Init Registry.NewPlugin(%s) error => %+v", id, err)
+			return err
+		}
+		p.AddPluginWithFactory(id, plugin, func() (Plugin, error) {
+			return registry.NewPlugin(id)
+		})
+	}
+
+	// forward packets along plugin chain, each hop supervised so a
+	// panicking or crash-looping plugin can't take the rest of the
+	// chain down with it
+	p.wirePluginChain()
+
+	if p.GetPluginsTotal() <= 0 {
+		return errInvalidPlugins
+	}
+	return nil
+}
+
+// wirePluginChain (re)starts the forwarding goroutine between every
+// adjacent pair of plugins currently in p.plugins, in order. It is
+// shared by Init and Reload.
+func (p *PluginChain) wirePluginChain() {
 	for i, plugin := range p.plugins {
+		sv := p.supervisors[plugin.ID()]
 		if i == 0 {
+			// the head plugin is the chain's source (fed directly by
+			// AttachPub, not by another plugin's forwardRTP goroutine),
+			// so it's never Supervised - mark it Running directly or
+			// Statuses() would report it stuck Starting forever.
+			sv.setState(StateRunning)
 			continue
 		}
-		go func(i int, plugin Plugin) {
-			for pkt := range p.plugins[i-1].ReadRTP() {
-				err := plugin.WriteRTP(pkt)
+		prevSv := p.supervisors[p.plugins[i-1].ID()]
+		sv.Supervise(func(done <-chan struct{}) error {
+			return forwardRTP(done, sv, prevSv)
+		})
+	}
+}
 
-				if err == io.ErrClosedPipe {
-					return
+// forwardRTP pumps packets from prevSv's plugin into sv's plugin until
+// its ReadRTP channel closes or done fires. Both are fetched fresh via
+// Plugin() on every iteration rather than captured once, because a
+// crash-restart with a factory installed (see Supervisor.SetFactory)
+// swaps in a new instance between forwardRTP invocations - reading a
+// stale reference here would keep writing into an already-dead plugin.
+// Any WriteRTP error - including io.ErrClosedPipe, which means the
+// plugin can no longer accept packets - is returned rather than
+// swallowed, so it feeds the supervisor's crash-restart/backoff/Failed
+// machinery instead of spinning forever against a plugin that will
+// never recover (e.g. a dead out-of-process plugin whose gRPC stream
+// returns a status error on every subsequent Send).
+func forwardRTP(done <-chan struct{}, sv, prevSv *Supervisor) error {
+	for {
+		select {
+		case <-done:
+			return nil
+		case pkt, ok := <-prevSv.Plugin().ReadRTP():
+			if !ok {
+				if prevSv.Failed() {
+					return errUpstreamFailed
 				}
+				return nil
+			}
 
-				if err != nil {
-					log.Errorf("Plugin Forward Packet error => %+v", err)
-				}
+			sv.IncPacketsIn()
+			if err := sv.Plugin().WriteRTP(pkt); err != nil {
+				sv.RecordError(err)
+				return err
 			}
-		}(i, plugin)
-	}
 
-	if p.GetPluginsTotal() <= 0 {
-		return errInvalidPlugins
+			sv.IncPacketsOut()
+		}
 	}
-	return nil
 }
 
 func (p *PluginChain) On() bool {
@@ -177,13 +285,78 @@ func (p *PluginChain) AttachPub(pub transport.Transport) {
 func (p *PluginChain) AddPlugin(id string, i Plugin) {
 	p.pluginLock.Lock()
 	defer p.pluginLock.Unlock()
+	p.addPluginLocked(id, i, nil)
+}
+
+// AddPluginWithFactory adds a plugin like AddPlugin, but also installs
+// factory as the way its supervisor rebuilds it after a crash instead
+// of retrying the same instance. Used for ExternalPlugin and
+// registry-discovered plugins, which can be recreated from their
+// original config; see Supervisor.SetFactory.
+func (p *PluginChain) AddPluginWithFactory(id string, i Plugin, factory func() (Plugin, error)) {
+	p.pluginLock.Lock()
+	defer p.pluginLock.Unlock()
+	p.addPluginLocked(id, i, factory)
+}
+
+func (p *PluginChain) addPluginLocked(id string, i Plugin, factory func() (Plugin, error)) {
 	p.plugins = append(p.plugins, i)
+	if p.supervisors == nil {
+		p.supervisors = make(map[string]*Supervisor)
+	}
+	sv := NewSupervisor(id, i)
+	if factory != nil {
+		sv.SetFactory(factory)
+		sv.OnRestart(func(plugin Plugin) {
+			p.replacePlugin(id, plugin)
+		})
+	}
+	p.supervisors[id] = sv
+}
+
+// replacePlugin swaps the chain's reference to the plugin with id for a
+// freshly-recreated instance. It's installed as the supervisor's
+// OnRestart callback, so - unlike the rest of this file's "Locked"
+// helpers - it acquires pluginLock itself: it runs from the
+// supervisor's own crash-restart goroutine, not from a caller that
+// already holds the lock.
+func (p *PluginChain) replacePlugin(id string, plugin Plugin) {
+	p.pluginLock.Lock()
+	defer p.pluginLock.Unlock()
+	for i := range p.plugins {
+		if p.plugins[i].ID() == id {
+			p.plugins[i] = plugin
+			return
+		}
+	}
+}
+
+// Wait registers fn to be called whenever a plugin in the chain
+// permanently dies after exhausting its crash-restart budget. Callers
+// such as the RTC transport that called AttachPub use this to react to
+// a dead plugin instead of it silently dropping packets. fn is
+// registered only with the plugins in the chain at the time of the
+// call; plugins added or recreated afterwards (by AddPlugin or Reload)
+// do not report to it.
+func (p *PluginChain) Wait(fn func(id string, err error)) {
+	p.pluginLock.RLock()
+	defer p.pluginLock.RUnlock()
+	for id, sv := range p.supervisors {
+		id := id
+		sv.Wait(func(err error) {
+			fn(id, err)
+		})
+	}
 }
 
 // GetPlugin get plugin by id
 func (p *PluginChain) GetPlugin(id string) Plugin {
 	p.pluginLock.RLock()
 	defer p.pluginLock.RUnlock()
+	return p.getPluginLocked(id)
+}
+
+func (p *PluginChain) getPluginLocked(id string) Plugin {
 	for i := 0; i < len(p.plugins); i++ {
 		if p.plugins[i].ID() == id {
 			return p.plugins[i]
@@ -203,10 +376,30 @@ func (p *PluginChain) GetPluginsTotal() int {
 func (p *PluginChain) DelPlugin(id string) {
 	p.pluginLock.Lock()
 	defer p.pluginLock.Unlock()
+	p.delPluginLocked(id)
+}
+
+func (p *PluginChain) delPluginLocked(id string) {
 	for i := 0; i < len(p.plugins); i++ {
 		if p.plugins[i].ID() == id {
+			// Cancel before Stop: the plugin's own supervisor is what
+			// runs the forwarding goroutine that calls WriteRTP on it
+			// (see wirePluginChain), so without joining it first it
+			// keeps writing to an already-Stop()'d plugin - burning
+			// through the crash-restart budget against a conn/process
+			// that's being torn down concurrently - instead of exiting
+			// cleanly. Same race Cancel was introduced for in Reload's
+			// rewireLocked.
+			if sv, ok := p.supervisors[id]; ok {
+				sv.Cancel()
+			}
 			p.plugins[i].Stop()
+			if sv, ok := p.supervisors[id]; ok {
+				sv.MarkStopped()
+			}
 			p.plugins = append(p.plugins[:i], p.plugins[i+1:]...)
+			delete(p.supervisors, id)
+			return
 		}
 	}
 }
@@ -215,10 +408,25 @@ func (p *PluginChain) DelPlugin(id string) {
 func (p *PluginChain) DelPluginChain() {
 	p.pluginLock.Lock()
 	defer p.pluginLock.Unlock()
+
+	// Cancel every forwarding goroutine before Stop()-ing any plugin,
+	// same reasoning as delPluginLocked: a plugin's own supervisor is
+	// the one writing into it, and joining it first avoids racing a
+	// still-exiting goroutine against the Stop() below.
+	for _, plugin := range p.plugins {
+		if sv, ok := p.supervisors[plugin.ID()]; ok {
+			sv.Cancel()
+		}
+	}
+
 	for _, plugin := range p.plugins {
 		plugin.Stop()
+		if sv, ok := p.supervisors[plugin.ID()]; ok {
+			sv.MarkStopped()
+		}
 	}
 	p.plugins = nil
+	p.supervisors = nil
 }
 
 func (p *PluginChain) Close() {
@@ -0,0 +1,179 @@
+package plugins
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pion/ion/pkg/log"
+)
+
+var errManifestEscapesDir = errors.New("plugin manifest executable path escapes the plugin directory")
+
+// PluginManifest is the on-disk description of a discoverable plugin,
+// read from a plugin.json next to its executable.
+type PluginManifest struct {
+	ID            string               `json:"id"`
+	Version       string               `json:"version"`
+	Type          string               `json:"type"`
+	Executable    string               `json:"executable"`
+	Capabilities  []string             `json:"capabilities"`
+	DefaultConfig ExternalPluginConfig `json:"defaultConfig"`
+}
+
+// RegisteredPlugin is a manifest plus its resolved, content-addressable
+// digest and verified absolute executable path.
+type RegisteredPlugin struct {
+	Manifest PluginManifest
+	Digest   string
+	Path     string
+}
+
+// PluginRegistry discovers plugins from manifests on disk so
+// PluginChain.Init can instantiate a plugin by ID instead of the
+// historical fixed set of "if config.X.On" branches.
+type PluginRegistry struct {
+	dir     string
+	plugins map[string]RegisteredPlugin
+}
+
+// NewPluginRegistry scans dir for immediate subdirectories containing a
+// plugin.json manifest, verifies each manifest's executable resolves to
+// a path inside dir, and registers it under a sha256 digest of the
+// executable bytes plus the manifest bytes. The digest, not the
+// manifest's self-reported ID, is what callers use to instantiate the
+// plugin - this is what avoids collisions between two plugins that
+// happen to pick the same name.
+func NewPluginRegistry(dir string) (*PluginRegistry, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve symlinks on the plugin directory itself too, not just on
+	// each manifest's executable: otherwise a plugin directory that
+	// sits under a symlinked prefix makes every valid plugin underneath
+	// it wrongly fail the withinDir prefix check below.
+	resolvedDir, err := filepath.EvalSymlinks(absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &PluginRegistry{
+		dir:     resolvedDir,
+		plugins: make(map[string]RegisteredPlugin),
+	}
+
+	manifests, err := filepath.Glob(filepath.Join(absDir, "*", "plugin.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, manifestPath := range manifests {
+		if err := r.load(manifestPath); err != nil {
+			log.Errorf("PluginRegistry: skipping %s => %+v", manifestPath, err)
+			continue
+		}
+	}
+
+	return r, nil
+}
+
+func (r *PluginRegistry) load(manifestPath string) error {
+	manifestBytes, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	var manifest PluginManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return err
+	}
+
+	execPath, err := filepath.Abs(filepath.Join(filepath.Dir(manifestPath), manifest.Executable))
+	if err != nil {
+		return err
+	}
+
+	resolved, err := filepath.EvalSymlinks(execPath)
+	if err != nil {
+		return err
+	}
+
+	if !withinDir(r.dir, resolved) {
+		return fmt.Errorf("%w: %s", errManifestEscapesDir, manifest.Executable)
+	}
+
+	execBytes, err := ioutil.ReadFile(resolved)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	h.Write(execBytes)
+	h.Write(manifestBytes)
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	r.plugins[digest] = RegisteredPlugin{
+		Manifest: manifest,
+		Digest:   digest,
+		Path:     resolved,
+	}
+
+	log.Infof("PluginRegistry: registered plugin id=%s digest=%s path=%s", manifest.ID, digest, resolved)
+	return nil
+}
+
+// withinDir reports whether target is dir itself or a descendant of
+// dir, once both are cleaned absolute paths. It's what rejects a
+// manifest executable that escapes the plugin directory via ".." or a
+// symlink.
+func withinDir(dir, target string) bool {
+	dir = filepath.Clean(dir)
+	target = filepath.Clean(target)
+	if target == dir {
+		return true
+	}
+	return strings.HasPrefix(target, dir+string(os.PathSeparator))
+}
+
+// Get looks up a registered plugin by its content digest.
+func (r *PluginRegistry) Get(digest string) (RegisteredPlugin, bool) {
+	p, ok := r.plugins[digest]
+	return p, ok
+}
+
+// List returns every plugin the registry discovered, keyed by digest.
+func (r *PluginRegistry) List() map[string]RegisteredPlugin {
+	out := make(map[string]RegisteredPlugin, len(r.plugins))
+	for k, v := range r.plugins {
+		out[k] = v
+	}
+	return out
+}
+
+// NewPlugin instantiates the registered plugin identified by digest.
+// Only TypeExternal manifests are supported today; the built-in
+// in-process kinds are still wired directly by PluginChain.Init.
+func (r *PluginRegistry) NewPlugin(digest string) (Plugin, error) {
+	reg, ok := r.Get(digest)
+	if !ok {
+		return nil, fmt.Errorf("PluginRegistry: unknown plugin id %s", digest)
+	}
+
+	switch reg.Manifest.Type {
+	case TypeExternal:
+		config := reg.Manifest.DefaultConfig
+		config.ID = digest
+		config.Path = reg.Path
+		return NewExternalPlugin(config)
+	default:
+		return nil, fmt.Errorf("PluginRegistry: unsupported plugin type %s", reg.Manifest.Type)
+	}
+}
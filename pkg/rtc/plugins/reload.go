@@ -0,0 +1,206 @@
+package plugins
+
+import (
+	"reflect"
+
+	"github.com/pion/ion/pkg/log"
+)
+
+// pluginOrder is the canonical JitterBuffer-first ordering Init and
+// Reload both build the chain in.
+var pluginOrder = []string{
+	TypeJitterBuffer,
+	TypeRTPForwarder,
+	TypeSampleBuilder,
+	TypeWebmSaver,
+	TypeExternal,
+}
+
+// Reload compares newConfig to the chain's current config field by
+// field and only starts, stops, or reconfigures the plugins whose
+// subconfig actually changed, instead of the Close+Init full teardown.
+// Untouched plugins are never Stop()'d, so JitterBuffer state and any
+// open WebmSaver file survive the reload; only the lightweight
+// forwarding goroutines are re-wired, in the same order Init uses.
+//
+// Reload itself is just a PluginChain method, not an RPC: whatever
+// calls it over the SFU's signaling/admin RPC, and how that caller
+// resolves a mid to a PluginChain, lives outside this package.
+func (p *PluginChain) Reload(newConfig Config) error {
+	p.pluginLock.Lock()
+	defer p.pluginLock.Unlock()
+
+	// Validate newConfig with CheckPlugins before touching anything:
+	// without this, turning JitterBuffer off while leaving an
+	// unchanged External on would silently promote External to head of
+	// the chain below, but AttachPub only ever wires the publisher into
+	// JitterBuffer or SampleBuilder - so the chain would come out of
+	// Reload alive and receiving nothing, with no error to say why.
+	if err := CheckPlugins(newConfig); err != nil {
+		return err
+	}
+
+	old := p.config
+	log.Infof("PluginChain.Reload old=%+v new=%+v", old, newConfig)
+
+	if !reflect.DeepEqual(old.JitterBuffer, newConfig.JitterBuffer) {
+		p.reloadPluginLocked(TypeJitterBuffer, newConfig.JitterBuffer.On, false, func() (Plugin, error) {
+			newConfig.JitterBuffer.ID = TypeJitterBuffer
+			return NewJitterBuffer(newConfig.JitterBuffer), nil
+		})
+	}
+
+	if !reflect.DeepEqual(old.RTPForwarder, newConfig.RTPForwarder) {
+		p.reloadPluginLocked(TypeRTPForwarder, newConfig.RTPForwarder.On, false, func() (Plugin, error) {
+			newConfig.RTPForwarder.ID = TypeRTPForwarder
+			newConfig.RTPForwarder.MID = p.mid
+			return NewRTPForwarder(newConfig.RTPForwarder), nil
+		})
+	}
+
+	if !reflect.DeepEqual(old.SampleBuilder, newConfig.SampleBuilder) {
+		p.reloadPluginLocked(TypeSampleBuilder, newConfig.SampleBuilder.On, false, func() (Plugin, error) {
+			newConfig.SampleBuilder.ID = TypeSampleBuilder
+			return NewSampleBuilder(newConfig.SampleBuilder), nil
+		})
+	}
+
+	if !reflect.DeepEqual(old.WebmSaver, newConfig.WebmSaver) {
+		p.reloadPluginLocked(TypeWebmSaver, newConfig.WebmSaver.On, false, func() (Plugin, error) {
+			newConfig.WebmSaver.ID = TypeWebmSaver
+			return NewWebmSaver(newConfig.WebmSaver), nil
+		})
+	}
+
+	var spawnErr error
+	if !reflect.DeepEqual(old.External, newConfig.External) {
+		p.reloadPluginLocked(TypeExternal, newConfig.External.On, true, func() (Plugin, error) {
+			newConfig.External.ID = TypeExternal
+			external, err := NewExternalPlugin(newConfig.External)
+			if err != nil {
+				spawnErr = err
+				return nil, err
+			}
+			return external, nil
+		})
+	}
+
+	p.reloadRegistryPluginsLocked(old.PluginIDs, newConfig.PluginIDs, newConfig.Registry)
+
+	newConfig.On = old.On
+	p.config = newConfig
+
+	p.rewireLocked()
+
+	if spawnErr != nil {
+		return spawnErr
+	}
+	if len(p.plugins) <= 0 {
+		return errInvalidPlugins
+	}
+	return nil
+}
+
+// reloadPluginLocked stops and removes the plugin id if it's currently
+// present, then recreates it via newPlugin if it should be on. Callers
+// hold pluginLock. A newPlugin error is logged and leaves the plugin
+// absent from the chain rather than aborting the rest of the Reload, matching
+// the non-fatal logging AttachPub already does for missing plugins.
+// restartable controls whether newPlugin is also installed as the
+// plugin's crash-restart factory (see Supervisor.SetFactory): true for
+// ExternalPlugin, which can actually be relaunched from its config;
+// false for the in-process kinds, which keep retrying the same instance
+// on crash like before this existed.
+func (p *PluginChain) reloadPluginLocked(id string, on, restartable bool, newPlugin func() (Plugin, error)) {
+	p.delPluginLocked(id)
+
+	if !on {
+		return
+	}
+
+	plugin, err := newPlugin()
+	if err != nil {
+		log.Errorf("PluginChain.Reload failed to start plugin %s => %+v", id, err)
+		return
+	}
+
+	if restartable {
+		p.addPluginLocked(id, plugin, newPlugin)
+	} else {
+		p.addPluginLocked(id, plugin, nil)
+	}
+}
+
+// reloadRegistryPluginsLocked diffs the registry-discovered plugin IDs
+// (content digests, not one of the canonical type names in pluginOrder)
+// the same way Reload diffs the five hard-coded subconfigs: an ID
+// removed from the list is stopped, an ID newly added is instantiated
+// via registry, and an ID present in both is left running untouched.
+func (p *PluginChain) reloadRegistryPluginsLocked(oldIDs, newIDs []string, registry *PluginRegistry) {
+	oldSet := make(map[string]bool, len(oldIDs))
+	for _, id := range oldIDs {
+		oldSet[id] = true
+	}
+	newSet := make(map[string]bool, len(newIDs))
+	for _, id := range newIDs {
+		newSet[id] = true
+	}
+
+	for id := range oldSet {
+		if !newSet[id] {
+			p.delPluginLocked(id)
+		}
+	}
+
+	for _, id := range newIDs {
+		if oldSet[id] {
+			continue
+		}
+		if registry == nil {
+			log.Errorf("PluginChain.Reload PluginIDs set but Registry is nil, skipping %s", id)
+			continue
+		}
+		id := id
+		plugin, err := registry.NewPlugin(id)
+		if err != nil {
+			log.Errorf("PluginChain.Reload Registry.NewPlugin(%s) error => %+v", id, err)
+			continue
+		}
+		p.addPluginLocked(id, plugin, func() (Plugin, error) {
+			return registry.NewPlugin(id)
+		})
+	}
+}
+
+// rewireLocked rebuilds p.plugins, then restarts every forwarding
+// goroutine in the rebuilt order. The five canonical kinds go first, in
+// pluginOrder, matching Init; any other plugin currently registered -
+// chiefly registry/PluginIDs plugins, whose IDs are content digests and
+// never appear in pluginOrder - is carried forward in its existing
+// relative order instead of being dropped. Unchanged plugins keep their
+// existing Supervisor (so Restarts/PacketsIn/PacketsOut counters stay
+// cumulative); only their forwarding goroutine is canceled and
+// re-launched against the (possibly new) neighbor.
+func (p *PluginChain) rewireLocked() {
+	seen := make(map[string]bool, len(pluginOrder))
+
+	ordered := make([]Plugin, 0, len(p.plugins))
+	for _, id := range pluginOrder {
+		if plugin := p.getPluginLocked(id); plugin != nil {
+			ordered = append(ordered, plugin)
+			seen[id] = true
+		}
+	}
+	for _, plugin := range p.plugins {
+		if !seen[plugin.ID()] {
+			ordered = append(ordered, plugin)
+		}
+	}
+	p.plugins = ordered
+
+	for _, plugin := range p.plugins {
+		p.supervisors[plugin.ID()].Cancel()
+	}
+
+	p.wirePluginChain()
+}
@@ -0,0 +1,58 @@
+package plugins
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// PluginState is the runtime lifecycle state of a single plugin inside
+// a PluginChain.
+type PluginState string
+
+const (
+	StateStarting PluginState = "Starting"
+	StateRunning  PluginState = "Running"
+	StateFailed   PluginState = "Failed"
+	StateStopped  PluginState = "Stopped"
+)
+
+// PluginStatus is a point-in-time snapshot of a plugin's health,
+// returned by PluginChain.Statuses so operators can see which plugins
+// are actually doing work for a given publisher.
+type PluginStatus struct {
+	ID         string      `json:"id"`
+	State      PluginState `json:"state"`
+	LastError  string      `json:"lastError,omitempty"`
+	Errors     uint64      `json:"errors"`
+	Restarts   int         `json:"restarts"`
+	PacketsIn  uint64      `json:"packetsIn"`
+	PacketsOut uint64      `json:"packetsOut"`
+}
+
+// Statuses returns a snapshot of every plugin currently in the chain,
+// keyed by plugin ID. Callers wanting status per-mid (e.g. an SFU admin
+// endpoint enumerating publishers) call this once per PluginChain and
+// key the result by the chain's mid.
+func (p *PluginChain) Statuses() map[string]PluginStatus {
+	p.pluginLock.RLock()
+	defer p.pluginLock.RUnlock()
+
+	out := make(map[string]PluginStatus, len(p.supervisors))
+	for id, sv := range p.supervisors {
+		out[id] = sv.Status()
+	}
+	return out
+}
+
+// ServeHTTP renders this chain's Statuses as JSON. It satisfies
+// http.Handler so an SFU admin mux can mount it under a per-mid path
+// (e.g. /admin/plugins/{mid}) to let operators poll plugin health
+// without a signaling round trip - but nothing in this package does
+// that mounting: it lives outside pkg/rtc/plugins, wherever the SFU
+// builds its admin mux and owns the mid->PluginChain lookup.
+func (p *PluginChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p.Statuses()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,371 @@
+package plugins
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/ion/pkg/log"
+)
+
+// These are vars rather than consts so tests can shrink the window and
+// backoff to keep crash-restart tests fast.
+var (
+	maxCrashRestarts = 5
+	crashWindow      = 30 * time.Second
+	initialBackoff   = time.Second
+	maxBackoff       = 4 * time.Second
+)
+
+// Supervisor runs a plugin's forwarding goroutine under recover(),
+// restarting it with capped exponential backoff when it crashes. If it
+// crashes more than maxCrashRestarts times within crashWindow, the
+// plugin is considered permanently Failed: the supervisor stops the
+// plugin and notifies anyone waiting via Wait, but leaves the rest of
+// PluginChain running.
+type Supervisor struct {
+	id string
+
+	packetsIn  uint64
+	packetsOut uint64
+	errors     uint64
+
+	mu        sync.Mutex
+	plugin    Plugin
+	newPlugin func() (Plugin, error)
+	onRestart func(Plugin)
+	state     PluginState
+	crashes   []time.Time
+	failed    bool
+	lastErr   error
+	waiters   []func(error)
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewSupervisor wraps plugin so its forwarding goroutine can be run via
+// Supervise.
+func NewSupervisor(id string, plugin Plugin) *Supervisor {
+	return &Supervisor{
+		id:     id,
+		plugin: plugin,
+		state:  StateStarting,
+	}
+}
+
+// SetFactory installs fn as the way this supervisor rebuilds its Plugin
+// after a crash, instead of retrying the same instance forever. Without
+// a factory, crash-restart only re-launches the forwarding goroutine -
+// fine for the in-process plugins, whose WriteRTP failing is usually
+// transient, but useless for an ExternalPlugin, where a crash typically
+// means the child process died and every retry just calls WriteRTP on
+// the same dead gRPC stream. PluginChain installs a factory for any
+// plugin it knows how to rebuild from its original config (ExternalPlugin,
+// registry-discovered plugins).
+func (s *Supervisor) SetFactory(fn func() (Plugin, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.newPlugin = fn
+}
+
+// OnRestart registers fn to be called with the freshly-rebuilt Plugin
+// whenever restartPlugin recreates it, so a caller holding its own
+// reference to the original instance (PluginChain.plugins) can swap it
+// too instead of forwarding packets into a stale one.
+func (s *Supervisor) OnRestart(fn func(Plugin)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRestart = fn
+}
+
+// Plugin returns the Plugin instance currently behind this supervisor.
+// It can change across a crash-restart when a factory is installed, so
+// forwardRTP re-fetches it every iteration rather than caching the
+// value from Supervise time.
+func (s *Supervisor) Plugin() Plugin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.plugin
+}
+
+// Supervise runs fn in its own goroutine, restarting it on panic until
+// fn returns nil (a clean exit, e.g. the upstream channel closed), the
+// plugin is marked Failed, or Cancel is called. fn is handed a done
+// channel it must select on so Cancel can stop it without touching the
+// underlying Plugin - see PluginChain.Reload.
+func (s *Supervisor) Supervise(fn func(done <-chan struct{}) error) {
+	s.mu.Lock()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	s.done = done
+	s.stopped = stopped
+	s.mu.Unlock()
+
+	s.setState(StateRunning)
+	go func() {
+		defer close(stopped)
+		s.run(fn, done)
+	}()
+}
+
+func (s *Supervisor) run(fn func(done <-chan struct{}) error, done chan struct{}) {
+	backoff := initialBackoff
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if s.Failed() {
+			return
+		}
+
+		err := s.runOnce(fn, done)
+		if err == nil {
+			return
+		}
+
+		if err == errUpstreamFailed {
+			// Nothing to retry: prevSv's plugin is gone for good, so
+			// every future forwardRTP attempt reads the same closed
+			// channel. Propagate the failure immediately instead of
+			// burning the crash-restart budget finding that out five
+			// times, and so Status reflects that this plugin stopped
+			// doing work instead of staying stuck Running forever.
+			log.Errorf("Supervisor(%s) upstream plugin failed, failing too", s.id)
+			s.fail(err)
+			return
+		}
+
+		log.Errorf("Supervisor(%s) plugin crashed => %+v", s.id, err)
+
+		if s.recordCrash(err) {
+			s.fail(err)
+			return
+		}
+
+		s.restartPlugin()
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// restartPlugin rebuilds the supervised Plugin via its factory, if one
+// was installed, so the next retry runs against a fresh instance
+// instead of the one that just crashed. It stops the old instance first
+// (a no-op if it already stopped itself by crashing) and notifies
+// OnRestart so PluginChain's own reference to the plugin doesn't go
+// stale. Plugins without a factory keep retrying the same instance,
+// same as before this existed.
+func (s *Supervisor) restartPlugin() {
+	s.mu.Lock()
+	newPlugin := s.newPlugin
+	onRestart := s.onRestart
+	old := s.plugin
+	s.mu.Unlock()
+
+	if newPlugin == nil {
+		return
+	}
+
+	old.Stop()
+
+	plugin, err := newPlugin()
+	if err != nil {
+		log.Errorf("Supervisor(%s) failed to recreate plugin => %+v", s.id, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.plugin = plugin
+	s.mu.Unlock()
+
+	if onRestart != nil {
+		// Dispatched async, not called inline: onRestart runs
+		// PluginChain.replacePlugin, which takes pluginLock - and
+		// Cancel() is called by DelPlugin/Close/Reload's rewireLocked
+		// while holding that same lock, then blocks on <-stopped until
+		// this run() goroutine exits. Calling onRestart synchronously
+		// here, from inside that same goroutine, would deadlock against
+		// a concurrent Cancel(): it can't get the lock until the caller
+		// unlocks, and the caller can't unlock until run() returns,
+		// which it can't do until restartPlugin (and thus onRestart)
+		// returns. A goroutine breaks that cycle at the cost of
+		// replacePlugin losing this race if the plugin is deleted or
+		// Reload'd away in the meantime - which replacePlugin already
+		// handles by simply finding nothing to replace.
+		go onRestart(plugin)
+	}
+}
+
+func (s *Supervisor) runOnce(fn func(done <-chan struct{}) error, done chan struct{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin %s panic: %v", s.id, r)
+		}
+	}()
+	return fn(done)
+}
+
+// Cancel gracefully stops the currently supervised forwarding goroutine
+// without stopping the underlying Plugin, and blocks until it has
+// actually exited before returning. That join matters: without it, a
+// caller that immediately re-Supervises the same plugin (PluginChain.
+// Reload re-wiring a neighbor) could end up racing a still-exiting
+// goroutine against the new one, e.g. two goroutines calling
+// ExternalPlugin.WriteRTP concurrently and corrupting its gRPC stream.
+// Used by PluginChain.Reload to re-wire a plugin whose upstream
+// neighbor changed while leaving its state (jitter buffer contents,
+// open WebM file, ...) untouched. The supervisor can be handed a new
+// forwarding func afterwards via Supervise.
+func (s *Supervisor) Cancel() {
+	s.mu.Lock()
+	done := s.done
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	if done == nil {
+		return
+	}
+
+	select {
+	case <-done:
+	default:
+		close(done)
+	}
+
+	if stopped != nil {
+		<-stopped
+	}
+}
+
+// recordCrash appends now to the rolling crash window, trims entries
+// older than crashWindow, and reports whether the crash threshold has
+// been exceeded.
+func (s *Supervisor) recordCrash(err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastErr = err
+
+	cutoff := time.Now().Add(-crashWindow)
+	kept := s.crashes[:0]
+	for _, t := range s.crashes {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	s.crashes = append(kept, time.Now())
+
+	return len(s.crashes) > maxCrashRestarts
+}
+
+func (s *Supervisor) fail(err error) {
+	s.mu.Lock()
+	s.failed = true
+	s.state = StateFailed
+	s.lastErr = err
+	waiters := s.waiters
+	s.waiters = nil
+	s.mu.Unlock()
+
+	s.plugin.Stop()
+
+	for _, w := range waiters {
+		w(err)
+	}
+}
+
+// MarkStopped records that the plugin was stopped deliberately (e.g.
+// via PluginChain.DelPlugin or Close) rather than by crashing.
+func (s *Supervisor) MarkStopped() {
+	s.setState(StateStopped)
+}
+
+// RecordError counts a WriteRTP error and records it as the most recent
+// one, so repeated failures are observable via Status even before (or
+// without) tripping the crash-restart logic.
+func (s *Supervisor) RecordError(err error) {
+	atomic.AddUint64(&s.errors, 1)
+	s.mu.Lock()
+	s.lastErr = err
+	s.mu.Unlock()
+}
+
+// IncPacketsIn counts a packet handed to this plugin's WriteRTP.
+func (s *Supervisor) IncPacketsIn() {
+	atomic.AddUint64(&s.packetsIn, 1)
+}
+
+// IncPacketsOut counts a packet this plugin's WriteRTP accepted without
+// error.
+func (s *Supervisor) IncPacketsOut() {
+	atomic.AddUint64(&s.packetsOut, 1)
+}
+
+func (s *Supervisor) setState(state PluginState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed {
+		return
+	}
+	s.state = state
+}
+
+// Status returns a point-in-time snapshot of this plugin's health.
+func (s *Supervisor) Status() PluginStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lastErr string
+	if s.lastErr != nil {
+		lastErr = s.lastErr.Error()
+	}
+
+	return PluginStatus{
+		ID:         s.id,
+		State:      s.state,
+		LastError:  lastErr,
+		Errors:     atomic.LoadUint64(&s.errors),
+		Restarts:   len(s.crashes),
+		PacketsIn:  atomic.LoadUint64(&s.packetsIn),
+		PacketsOut: atomic.LoadUint64(&s.packetsOut),
+	}
+}
+
+// Failed reports whether the plugin has been permanently stopped after
+// exceeding the crash threshold.
+func (s *Supervisor) Failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
+// Restarts reports how many crashes are currently counted in the
+// rolling window.
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.crashes)
+}
+
+// Wait registers fn to be called when the plugin permanently dies. If
+// it has already died, fn is called immediately with the error that
+// killed it.
+func (s *Supervisor) Wait(fn func(error)) {
+	s.mu.Lock()
+	if s.failed {
+		err := s.lastErr
+		s.mu.Unlock()
+		fn(err)
+		return
+	}
+	s.waiters = append(s.waiters, fn)
+	s.mu.Unlock()
+}
@@ -0,0 +1,201 @@
+package plugins
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+type fakePlugin struct {
+	stopped chan struct{}
+}
+
+func newFakePlugin() *fakePlugin {
+	return &fakePlugin{stopped: make(chan struct{})}
+}
+
+func (f *fakePlugin) ID() string                    { return "fake" }
+func (f *fakePlugin) WriteRTP(*rtp.Packet) error    { return nil }
+func (f *fakePlugin) ReadRTP() <-chan *rtp.Packet   { return nil }
+func (f *fakePlugin) Stop()                         { close(f.stopped) }
+
+// withFastBackoff shrinks the crash-restart window/backoff for the
+// duration of a test so it doesn't have to wait out the real multi-
+// second backoff schedule.
+func withFastBackoff(t *testing.T) {
+	t.Helper()
+	origRestarts, origWindow, origInitial, origMax := maxCrashRestarts, crashWindow, initialBackoff, maxBackoff
+	maxCrashRestarts = 2
+	crashWindow = time.Minute
+	initialBackoff = time.Millisecond
+	maxBackoff = 5 * time.Millisecond
+	t.Cleanup(func() {
+		maxCrashRestarts, crashWindow, initialBackoff, maxBackoff = origRestarts, origWindow, origInitial, origMax
+	})
+}
+
+func TestSupervisorMarksFailedAfterCrashThreshold(t *testing.T) {
+	withFastBackoff(t)
+
+	plugin := newFakePlugin()
+	sv := NewSupervisor("fake", plugin)
+
+	died := make(chan error, 1)
+	sv.Wait(func(err error) { died <- err })
+
+	sv.Supervise(func(done <-chan struct{}) error {
+		return errors.New("boom")
+	})
+
+	select {
+	case err := <-died:
+		if err == nil || err.Error() != "boom" {
+			t.Fatalf("expected Wait to report the last crash error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("supervisor never reported failure")
+	}
+
+	if !sv.Failed() {
+		t.Fatal("expected supervisor to be marked Failed")
+	}
+
+	if want := maxCrashRestarts + 1; sv.Restarts() != want {
+		t.Fatalf("expected %d restarts, got %d", want, sv.Restarts())
+	}
+
+	select {
+	case <-plugin.stopped:
+	default:
+		t.Fatal("expected the underlying plugin to have been stopped")
+	}
+}
+
+func TestSupervisorWaitCalledImmediatelyIfAlreadyFailed(t *testing.T) {
+	withFastBackoff(t)
+
+	plugin := newFakePlugin()
+	sv := NewSupervisor("fake", plugin)
+
+	done := make(chan struct{})
+	sv.Supervise(func(done <-chan struct{}) error {
+		return errors.New("boom")
+	})
+
+	for !sv.Failed() {
+		time.Sleep(time.Millisecond)
+	}
+
+	sv.Wait(func(err error) { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait registered after failure should fire immediately")
+	}
+}
+
+func TestSupervisorFailsImmediatelyWhenUpstreamFailed(t *testing.T) {
+	withFastBackoff(t)
+
+	plugin := newFakePlugin()
+	sv := NewSupervisor("fake", plugin)
+
+	died := make(chan error, 1)
+	sv.Wait(func(err error) { died <- err })
+
+	sv.Supervise(func(done <-chan struct{}) error {
+		return errUpstreamFailed
+	})
+
+	select {
+	case err := <-died:
+		if err != errUpstreamFailed {
+			t.Fatalf("expected Wait to report errUpstreamFailed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("supervisor never reported failure")
+	}
+
+	if !sv.Failed() {
+		t.Fatal("expected supervisor to be marked Failed")
+	}
+
+	if sv.Restarts() != 0 {
+		t.Fatalf("expected no crash-restart attempts against a dead upstream, got %d", sv.Restarts())
+	}
+}
+
+func TestSupervisorRestartsPluginViaFactory(t *testing.T) {
+	withFastBackoff(t)
+
+	first := newFakePlugin()
+	second := newFakePlugin()
+	built := []*fakePlugin{second}
+
+	sv := NewSupervisor("fake", first)
+	sv.SetFactory(func() (Plugin, error) {
+		if len(built) == 0 {
+			return nil, errors.New("no more plugins to build")
+		}
+		next := built[0]
+		built = built[1:]
+		return next, nil
+	})
+
+	var restarted Plugin
+	sv.OnRestart(func(p Plugin) { restarted = p })
+
+	crashed := false
+	sv.Supervise(func(done <-chan struct{}) error {
+		if !crashed {
+			crashed = true
+			return errors.New("boom")
+		}
+		<-done
+		return nil
+	})
+
+	select {
+	case <-first.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected the crashed plugin to be stopped before recreating it")
+	}
+
+	for restarted == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	if restarted != second {
+		t.Fatalf("expected OnRestart to report the rebuilt plugin, got %v", restarted)
+	}
+
+	if got := sv.Plugin(); got != second {
+		t.Fatalf("expected Supervisor.Plugin() to return the rebuilt instance, got %v", got)
+	}
+
+	sv.Cancel()
+}
+
+func TestSupervisorCancelJoinsForwardingGoroutine(t *testing.T) {
+	plugin := newFakePlugin()
+	sv := NewSupervisor("fake", plugin)
+
+	exited := make(chan struct{})
+	sv.Supervise(func(done <-chan struct{}) error {
+		<-done
+		time.Sleep(20 * time.Millisecond)
+		close(exited)
+		return nil
+	})
+
+	sv.Cancel()
+
+	select {
+	case <-exited:
+	default:
+		t.Fatal("Cancel returned before the forwarding goroutine actually exited")
+	}
+}